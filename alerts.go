@@ -0,0 +1,98 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// direction is which side of a threshold an Alert should trigger on.
+type direction string
+
+const (
+	above direction = ">"
+	below direction = "<"
+)
+
+// Alert is a user-registered price threshold condition for a symbol,
+// e.g. "alert AAPL > 180.00". Fired tracks whether the condition is
+// currently crossed so updateStock only triggers the alert's actions once
+// per crossing.
+type Alert struct {
+	Symbol    string    `json:"symbol"`
+	Direction direction `json:"direction"`
+	Threshold float64   `json:"threshold"`
+	Fired     bool      `json:"fired"`
+}
+
+// crossed reports whether price satisfies the alert's condition.
+func (a *Alert) crossed(price float64) bool {
+	switch a.Direction {
+	case above:
+		return price > a.Threshold
+	case below:
+		return price < a.Threshold
+	default:
+		return false
+	}
+}
+
+// parseAlert parses a command line of the form "alert SYMBOL > 180.00".
+func parseAlert(cmd string) (Alert, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) != 4 {
+		return Alert{}, fmt.Errorf("usage: alert SYMBOL <|> THRESHOLD")
+	}
+
+	dir := direction(fields[2])
+	if dir != above && dir != below {
+		return Alert{}, fmt.Errorf("alert direction must be > or <")
+	}
+
+	threshold, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		return Alert{}, fmt.Errorf("invalid alert threshold %q: %w", fields[3], err)
+	}
+
+	return Alert{
+		Symbol:    strings.ToUpper(fields[1]),
+		Direction: dir,
+		Threshold: threshold,
+	}, nil
+}
+
+// fire runs an alert's actions: ringing the terminal bell and, if
+// configured, shelling out to the user's -on-alert command.
+func fire(a *Alert, price float64, onAlert string) {
+	ringBell()
+	if onAlert == "" {
+		return
+	}
+
+	cmd := strings.NewReplacer("%s", a.Symbol, "%f", strconv.FormatFloat(price, 'f', 2, 64)).Replace(onAlert)
+	go func() {
+		if err := exec.Command("sh", "-c", cmd).Run(); err != nil {
+			log.Println("on-alert command failed:", err)
+		}
+	}()
+}
+
+// ringBell writes the terminal bell character so the alert is audible.
+func ringBell() {
+	fmt.Fprint(os.Stdout, "\a")
+}
@@ -0,0 +1,108 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// prompt is the text shown before the command line's typed buffer.
+const prompt = "> "
+
+// commandLine is a single-line editor rendered at the bottom of the
+// screen, modeled after the line editor mop uses for its own watchlist
+// commands. Typing "+AAPL", "-TSLA", "refresh" or "quit" and pressing
+// Enter submits the command; 'a', 'd' and 'q' are shortcuts that start
+// add, delete and quit respectively when the line is empty.
+type commandLine struct {
+	buffer []rune
+}
+
+// handleEvent folds a termbox key event into the command line's state.
+// It returns the submitted command (if Enter was pressed) and whether
+// the event means the application should quit.
+func (c *commandLine) handleEvent(ev termbox.Event) (cmd string, quit bool) {
+	if ev.Type != termbox.EventKey {
+		return "", false
+	}
+
+	switch ev.Key {
+	case termbox.KeyEnter:
+		cmd = strings.TrimSpace(string(c.buffer))
+		c.buffer = c.buffer[:0]
+		if cmd == "quit" {
+			return "", true
+		}
+		return cmd, false
+	case termbox.KeyEsc:
+		c.buffer = c.buffer[:0]
+		return "", false
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(c.buffer) > 0 {
+			c.buffer = c.buffer[:len(c.buffer)-1]
+		}
+		return "", false
+	}
+
+	if len(c.buffer) == 0 {
+		switch ev.Ch {
+		case 'a':
+			c.buffer = append(c.buffer, '+')
+			return "", false
+		case 'd':
+			c.buffer = append(c.buffer, '-')
+			return "", false
+		case 'q':
+			return "", true
+		}
+	}
+
+	if ev.Ch != 0 {
+		c.buffer = append(c.buffer, ev.Ch)
+	}
+	return "", false
+}
+
+// render draws the command line at the given row.
+func (c *commandLine) render(y int) {
+	printSeg(0, y, prompt+string(c.buffer), termbox.ColorYellow, termbox.ColorDefault)
+}
+
+// execute applies a submitted command to the watcher. Unrecognized
+// commands are ignored.
+func execute(t *stockwatcher, ctx context.Context, cmd string) {
+	switch {
+	case cmd == "refresh":
+		if t.streaming {
+			t.notifyChange() // resubscribe rather than poll
+		} else {
+			t.runner(ctx)
+		}
+	case strings.HasPrefix(cmd, "+"):
+		if symbol := strings.ToUpper(strings.TrimPrefix(cmd, "+")); symbol != "" {
+			t.add(symbol)
+		}
+	case strings.HasPrefix(cmd, "-"):
+		if symbol := strings.ToUpper(strings.TrimPrefix(cmd, "-")); symbol != "" {
+			t.remove(symbol)
+		}
+	case strings.HasPrefix(cmd, "alert "):
+		if a, err := parseAlert(cmd); err == nil {
+			t.add(a.Symbol)
+			t.addAlert(a)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	dbFlag         = flag.String("db", "", "Path to the tick history database (default ~/.stockticker.db)")
+	historyFlag    = flag.Int("history", 60, "Number of samples kept per symbol in the tick history database")
+	sparkWidthFlag = flag.Int("spark-width", 20, "Width in columns of the sparkline column, capped to fit the terminal")
+	exportFlag     = flag.String("export", "", "Export stored ticks in the given format (csv) and exit")
+)
+
+// tickStore persists every observed price so history survives restarts
+// and powers the sparkline column.
+type tickStore struct {
+	db     *sql.DB
+	retain int // samples kept per symbol; record prunes older rows once exceeded. <=0 means record stores nothing.
+}
+
+// defaultDBPath returns the default location of the tick history
+// database, ~/.stockticker.db.
+func defaultDBPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stockticker.db"), nil
+}
+
+// openTickStore opens (creating if necessary) the SQLite database at
+// path and ensures the ticks table exists. retain is the number of
+// samples record keeps per symbol before pruning older rows; <=0 means
+// record stores nothing, matching -history's "samples kept" description.
+// The -export path passes 0 since it only reads, never records.
+func openTickStore(path string, retain int) (*tickStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	const schema = `CREATE TABLE IF NOT EXISTS ticks (
+		symbol TEXT NOT NULL,
+		ts     INTEGER NOT NULL,
+		price  REAL NOT NULL
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &tickStore{db: db, retain: retain}, nil
+}
+
+// record saves a single tick, then prunes rows for symbol older than the
+// store's retain limit so the table doesn't grow without bound. It's a
+// no-op when retain is <=0.
+func (s *tickStore) record(symbol string, ts time.Time, price float64) error {
+	if s.retain <= 0 {
+		return nil
+	}
+	if _, err := s.db.Exec(`INSERT INTO ticks (symbol, ts, price) VALUES (?, ?, ?)`, symbol, ts.Unix(), price); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM ticks WHERE symbol = ? AND rowid NOT IN (
+		SELECT rowid FROM ticks WHERE symbol = ? ORDER BY ts DESC LIMIT ?
+	)`, symbol, symbol, s.retain)
+	return err
+}
+
+// recent returns up to n of the most recent prices for symbol, oldest
+// first.
+func (s *tickStore) recent(symbol string, n int) ([]float64, error) {
+	rows, err := s.db.Query(`SELECT price FROM ticks WHERE symbol = ? ORDER BY ts DESC LIMIT ?`, symbol, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []float64
+	for rows.Next() {
+		var price float64
+		if err := rows.Scan(&price); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+
+	// reverse into chronological order
+	for i, j := 0, len(prices)-1; i < j; i, j = i+1, j-1 {
+		prices[i], prices[j] = prices[j], prices[i]
+	}
+	return prices, rows.Err()
+}
+
+// exportCSV writes every stored tick to w as CSV.
+func (s *tickStore) exportCSV(w io.Writer) error {
+	rows, err := s.db.Query(`SELECT symbol, ts, price FROM ticks ORDER BY symbol, ts`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"symbol", "ts", "price"}); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			symbol string
+			ts     int64
+			price  float64
+		)
+		if err := rows.Scan(&symbol, &ts, &price); err != nil {
+			return err
+		}
+		if err := cw.Write([]string{symbol, strconv.FormatInt(ts, 10), strconv.FormatFloat(price, 'f', 2, 64)}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Close closes the underlying database handle.
+func (s *tickStore) Close() error {
+	return s.db.Close()
+}
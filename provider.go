@@ -0,0 +1,75 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quote is a normalized snapshot of a single symbol as returned by a
+// QuoteProvider. Providers populate whatever subset of fields their
+// backend actually exposes; callers should treat the zero value of a
+// numeric field as "unknown" rather than a real reading.
+type Quote struct {
+	Symbol         string
+	Price          float64
+	PreviousClose  float64
+	Change         float64
+	ChangePercent  float64
+	Open           float64
+	DayHigh        float64
+	DayLow         float64
+	FiftyTwoWkLow  float64
+	FiftyTwoWkHigh float64
+	Volume         uint64
+	MarketState    string
+}
+
+// QuoteProvider fetches the latest quotes for a set of symbols. A single
+// call to Fetch may satisfy the request with one HTTP round trip (as
+// yahooV7Provider does) or fan out a request per symbol (as
+// yahooProvider does); either way the caller gets back at most one
+// Quote per symbol it asked for, and a symbol that couldn't be fetched
+// may be missing from the result. runner() polls a QuoteProvider on the
+// -i interval.
+type QuoteProvider interface {
+	Fetch(ctx context.Context, symbols []string) ([]Quote, error)
+}
+
+// StreamingProvider pushes quotes for the given symbols onto the
+// returned channel as they arrive, rather than waiting to be polled.
+// The channel is closed once ctx is done or the underlying connection
+// is lost. Calling Stream again (e.g. after the watchlist changes)
+// starts a fresh subscription.
+type StreamingProvider interface {
+	Stream(ctx context.Context, symbols []string) (<-chan Quote, error)
+}
+
+// newProvider returns the provider registered under name. It's either a
+// QuoteProvider (polled on an interval) or a StreamingProvider (pushes
+// updates as they arrive) - callers type-switch on the result.
+func newProvider(name string) (any, error) {
+	switch name {
+	case "yahoo":
+		return newYahooProvider(), nil
+	case "yahoov7":
+		return newYahooV7Provider(), nil
+	case "mqtt":
+		return newMQTTProvider(*mqttBrokerFlag, *mqttTopicFlag), nil
+	case "ws":
+		return newWSProvider(*wsURLFlag, *wsKeyFlag, *wsSecretFlag), nil
+	default:
+		return nil, fmt.Errorf("unknown quote provider %q", name)
+	}
+}
@@ -0,0 +1,82 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var (
+	mqttBrokerFlag = flag.String("mqtt-broker", "tcp://localhost:1883", "MQTT broker address, used by the mqtt provider")
+	mqttTopicFlag  = flag.String("mqtt-topic", "stock/response/#", "MQTT topic to subscribe to, used by the mqtt provider")
+)
+
+// mqttProvider is a StreamingProvider backed by an MQTT topic that
+// publishes one JSON-encoded Quote per message.
+type mqttProvider struct {
+	broker string
+	topic  string
+}
+
+// newMQTTProvider returns a StreamingProvider that subscribes to topic
+// on broker.
+func newMQTTProvider(broker, topic string) *mqttProvider {
+	return &mqttProvider{broker: broker, topic: topic}
+}
+
+// Stream connects to the broker and subscribes to the configured topic,
+// decoding each message payload into a Quote. The symbols argument is
+// unused: the topic itself determines what's published, filtering by
+// watchlist happens upstream in stockwatcher. The channel is closed when
+// ctx is done or the connection to the broker is lost.
+func (p *mqttProvider) Stream(ctx context.Context, symbols []string) (<-chan Quote, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	opts := mqtt.NewClientOptions().AddBroker(p.broker).SetClientID("stockticker").
+		SetConnectionLostHandler(func(mqtt.Client, error) { cancel() })
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		cancel()
+		return nil, token.Error()
+	}
+
+	quotes := make(chan Quote)
+	handler := func(_ mqtt.Client, msg mqtt.Message) {
+		var q Quote
+		if err := json.Unmarshal(msg.Payload(), &q); err != nil {
+			return
+		}
+		select {
+		case quotes <- q:
+		case <-ctx.Done():
+		}
+	}
+
+	if token := client.Subscribe(p.topic, 0, handler); token.Wait() && token.Error() != nil {
+		client.Disconnect(250)
+		cancel()
+		return nil, token.Error()
+	}
+
+	go func() {
+		<-ctx.Done()
+		client.Unsubscribe(p.topic)
+		client.Disconnect(250)
+		close(quotes)
+	}()
+
+	return quotes, nil
+}
@@ -0,0 +1,164 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+const yahooURL = "http://finance.yahoo.com/webservice/v1/symbols/%s/quote?format=json" // where we're getting our data from
+
+var priceRe = regexp.MustCompile(`^\d.+\.\d{2}`) // this is to have only 2 decimal places
+
+// yahooStock is the top level of the JSON returned by the legacy Yahoo
+// webservice endpoint.
+type yahooStock struct {
+	List yahooList `json:"list"`
+}
+
+// yahooList holds the metadata and list of returned symbol data.
+type yahooList struct {
+	Meta      yahooMeta       `json:"meta"`
+	Resources []yahooResource `json:"resources"`
+}
+
+// yahooMeta is the call's metadata.
+type yahooMeta struct {
+	Type  string `json:"type"`
+	Start uint   `json:"start"`
+	Count uint   `json:"count"`
+}
+
+// yahooResource holds a JSON obj with the symbol data.
+type yahooResource struct {
+	Resource yahooResourceFields `json:"resource"`
+}
+
+// yahooResourceFields contains the actual JSON obj with the symbol data.
+type yahooResourceFields struct {
+	Classname string      `json:"classname"`
+	Fields    yahooFields `json:"fields"`
+}
+
+// yahooFields holds all of the retrieved data from the API call.
+type yahooFields struct {
+	Name    string `json:"name"`    // name of company
+	Price   string `json:"price"`   // current price
+	Symbol  string `json:"symbol"`  // stock symbol
+	TS      string `json:"ts"`      //
+	Type    string `json:"type"`    // type of stock (equity, etc...)
+	UTCTime string `json:"utctime"` // time in UTC
+	Volume  string `json:"volume"`  // shares traded
+}
+
+// yahooProvider is the original per-symbol Yahoo webservice backend. It
+// fans out one HTTP request per symbol since the endpoint only accepts a
+// single symbol at a time.
+type yahooProvider struct {
+	client *http.Client
+}
+
+// newYahooProvider returns a QuoteProvider backed by the legacy
+// finance.yahoo.com webservice endpoint.
+func newYahooProvider() *yahooProvider {
+	return &yahooProvider{
+		client: &http.Client{Timeout: TIMEOUT},
+	}
+}
+
+// Fetch retrieves a quote for each symbol concurrently, one request per
+// symbol, and assembles the results into a single slice. A symbol whose
+// request fails is dropped from the result rather than reported as a
+// zeroed Quote, so a transient HTTP error can't be mistaken for the
+// price actually going to 0 by updateStock's alert and history logic.
+func (p *yahooProvider) Fetch(ctx context.Context, symbols []string) ([]Quote, error) {
+	var (
+		wg     sync.WaitGroup
+		m      sync.Mutex
+		quotes = make([]Quote, 0, len(symbols))
+	)
+
+	for _, symbol := range symbols {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			q, err := p.fetchOne(ctx, symbol)
+			if err != nil { // if we can't get a response from the API, skip it and keep the last known value
+				log.Println("unable to fetch quote for", symbol, "-", err)
+				return
+			}
+			m.Lock()
+			quotes = append(quotes, q)
+			m.Unlock()
+		}(symbol)
+	}
+	wg.Wait()
+
+	return quotes, nil
+}
+
+// fetchOne retrieves and parses the quote for a single symbol.
+func (p *yahooProvider) fetchOne(ctx context.Context, symbol string) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(yahooURL, symbol), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Quote{}, errors.New("unable to retrieve symbol data")
+	}
+	defer resp.Body.Close()
+
+	var data yahooStock
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return Quote{}, fmt.Errorf("decoding response for %s: %w", symbol, err)
+	}
+	if len(data.List.Resources) == 0 {
+		return Quote{}, errors.New("no data returned for symbol")
+	}
+
+	fields := data.List.Resources[0].Resource.Fields
+	price, err := convertPrice(priceRe.FindString(fields.Price))
+	if err != nil {
+		return Quote{}, fmt.Errorf("parsing price for %s: %w", symbol, err)
+	}
+	return Quote{
+		Symbol: fields.Symbol,
+		Price:  price,
+		Volume: convertVolume(fields.Volume),
+	}, nil
+}
+
+// convertPrice converts the given string to a float64 value.
+func convertPrice(p string) (float64, error) {
+	return strconv.ParseFloat(p, 64)
+}
+
+// convertVolume converts the given string to a uint64 value, returning 0
+// if it can't be parsed rather than aborting the whole fetch.
+func convertVolume(v string) uint64 {
+	volume, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return volume
+}
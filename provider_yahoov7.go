@@ -0,0 +1,117 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const yahooV7URL = "https://query1.finance.yahoo.com/v7/finance/quote?symbols=%s" // batched quote endpoint
+
+// yahooV7Response is the top level of the JSON returned by the v7
+// finance.yahoo.com quote endpoint.
+type yahooV7Response struct {
+	QuoteResponse yahooV7QuoteResponse `json:"quoteResponse"`
+}
+
+// yahooV7QuoteResponse holds the batch of results and any error reported
+// by the API itself.
+type yahooV7QuoteResponse struct {
+	Result []yahooV7Quote `json:"result"`
+	Error  interface{}    `json:"error"`
+}
+
+// yahooV7Quote is a single symbol's entry in the v7 response. Only the
+// fields this tool cares about are modeled; the real payload carries
+// many more.
+type yahooV7Quote struct {
+	Symbol                     string  `json:"symbol"`
+	RegularMarketPrice         float64 `json:"regularMarketPrice"`
+	RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
+	RegularMarketChange        float64 `json:"regularMarketChange"`
+	RegularMarketChangePercent float64 `json:"regularMarketChangePercent"`
+	RegularMarketOpen          float64 `json:"regularMarketOpen"`
+	RegularMarketDayHigh       float64 `json:"regularMarketDayHigh"`
+	RegularMarketDayLow        float64 `json:"regularMarketDayLow"`
+	FiftyTwoWeekLow            float64 `json:"fiftyTwoWeekLow"`
+	FiftyTwoWeekHigh           float64 `json:"fiftyTwoWeekHigh"`
+	RegularMarketVolume        uint64  `json:"regularMarketVolume"`
+	MarketState                string  `json:"marketState"`
+}
+
+// yahooV7Provider is the modern batched Yahoo finance quote backend. It
+// retrieves every requested symbol in a single HTTP call.
+type yahooV7Provider struct {
+	client *http.Client
+}
+
+// newYahooV7Provider returns a QuoteProvider backed by the
+// query1.finance.yahoo.com v7 quote endpoint.
+func newYahooV7Provider() *yahooV7Provider {
+	return &yahooV7Provider{
+		client: &http.Client{Timeout: TIMEOUT},
+	}
+}
+
+// Fetch retrieves quotes for all of the given symbols in a single
+// request.
+func (p *yahooV7Provider) Fetch(ctx context.Context, symbols []string) ([]Quote, error) {
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	url := fmt.Sprintf(yahooV7URL, strings.Join(symbols, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; stockticker/1.0)")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.New("unable to retrieve quote data")
+	}
+	defer resp.Body.Close()
+
+	var data yahooV7Response
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.QuoteResponse.Error != nil {
+		return nil, fmt.Errorf("yahoo v7 quote error: %v", data.QuoteResponse.Error)
+	}
+
+	quotes := make([]Quote, 0, len(data.QuoteResponse.Result))
+	for _, r := range data.QuoteResponse.Result {
+		quotes = append(quotes, Quote{
+			Symbol:         r.Symbol,
+			Price:          r.RegularMarketPrice,
+			PreviousClose:  r.RegularMarketPreviousClose,
+			Change:         r.RegularMarketChange,
+			ChangePercent:  r.RegularMarketChangePercent,
+			Open:           r.RegularMarketOpen,
+			DayHigh:        r.RegularMarketDayHigh,
+			DayLow:         r.RegularMarketDayLow,
+			FiftyTwoWkLow:  r.FiftyTwoWeekLow,
+			FiftyTwoWkHigh: r.FiftyTwoWeekHigh,
+			Volume:         r.RegularMarketVolume,
+			MarketState:    r.MarketState,
+		})
+	}
+	return quotes, nil
+}
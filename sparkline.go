@@ -0,0 +1,51 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "strings"
+
+// sparkBlocks are the eight levels a sample can be bucketed into,
+// lowest to highest.
+var sparkBlocks = [...]rune{'▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// sparkline renders samples as a string of Unicode block characters
+// scaled between their observed min and max. Fewer than two samples
+// can't show a trend, so it renders spaces instead.
+func sparkline(samples []float64, width int) string {
+	if len(samples) < 2 {
+		return strings.Repeat(" ", width)
+	}
+
+	min, max := samples[0], samples[0]
+	for _, s := range samples {
+		if s < min {
+			min = s
+		}
+		if s > max {
+			max = s
+		}
+	}
+
+	out := make([]rune, 0, width)
+	for i := len(samples); i < width; i++ {
+		out = append(out, ' ') // not enough history yet to fill the column
+	}
+	for _, s := range samples {
+		level := 0
+		if max > min {
+			level = int((s - min) / (max - min) * float64(len(sparkBlocks)-1))
+		}
+		out = append(out, sparkBlocks[level])
+	}
+	return string(out)
+}
@@ -14,14 +14,11 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -32,142 +29,225 @@ import (
 	"github.com/nsf/termbox-go"
 )
 
-const TIMEOUT = time.Duration(time.Second * 10)                                   // how long to wait on a call
-const URL = "http://finance.yahoo.com/webservice/v1/symbols/%s/quote?format=json" // where we're getting our data from
-const UP = "↑"                                                                    // rune 8593
-const DOWN = "↓"                                                                  // rune 8595
+const TIMEOUT = time.Duration(time.Second * 10) // how long to wait on a call
+const UP = "↑"                                  // rune 8593
+const DOWN = "↓"                                // rune 8595
 
-var re = regexp.MustCompile(`^\d.+\.\d{2}`) // this is to have only 2 decimal places
-var signalChan = make(chan os.Signal, 1)    // channel to catch ctrl-c
+var signalChan = make(chan os.Signal, 1) // channel to catch ctrl-c
 
 // Flag variables to hold CLI arguments
 var (
-	symbolFlag   = flag.String("s", "", "Symbols for ticker, comma seperate (no spaces)")
-	intervalFlag = flag.Int("i", 1, "Interval for stock data to be updated in seconds")
+	intervalFlag  = flag.Int("i", 1, "Interval for stock data to be updated in seconds (REST providers only; ignored by streaming providers)")
+	providerFlag  = flag.String("provider", "yahoov7", "Quote provider to use (yahoo, yahoov7, mqtt, ws)")
+	watchlistFlag = flag.String("w", "", "Path to the watchlist file (default ~/.stockticker)")
+	onAlertFlag   = flag.String("on-alert", "", "Shell command to run when an alert fires; %s and %f are replaced with the symbol and price")
 )
 
-// Stock is the top level of the returned JSON
-type Stock struct {
-	List List `json:"list"`
-}
-
-// List hold the metadata and list of returned symbol data
-type List struct {
-	Meta      Meta        `json:"meta"`
-	Resources []Resources `json:"resources"`
+// stockwatcher holds the relevant data for the running instance
+type stockwatcher struct {
+	quotes     map[string]*Quote
+	prevPrice  map[string]float64
+	interval   time.Duration
+	provider   QuoteProvider
+	path       string  // where the watchlist is persisted
+	alerts     []Alert // price conditions, evaluated on every updateStock
+	onAlert    string  // shell command template run when an alert fires
+	flashed    map[string]bool
+	streaming  bool       // true when provider is a StreamingProvider; -i is a REST fallback only
+	onChange   func()     // notified after add/remove, used to resubscribe a StreamingProvider
+	history    *tickStore // optional tick history backing the sparkline column
+	sparkWidth int        // requested sparkline column width, capped to the terminal in formatData
+	m          *sync.Mutex
 }
 
-// Meta is the calls metadata
-type Meta struct {
-	Type  string `json:"type"`
-	Start uint   `json:"start"`
-	Count uint   `json:"count"`
+// NewStockWatcher returns a new instance of stockwatcher with the given parameters
+func NewStockWatcher(i time.Duration, p QuoteProvider, path, onAlert string) *stockwatcher {
+	return &stockwatcher{
+		quotes:    make(map[string]*Quote),
+		prevPrice: make(map[string]float64),
+		interval:  i,
+		provider:  p,
+		path:      path,
+		onAlert:   onAlert,
+		flashed:   make(map[string]bool),
+		m:         &sync.Mutex{},
+	}
 }
 
-// Resources holds a JSON obj with the symbol data
-type Resources struct {
-	Resource Resource `json:"resource"`
+// seed populates the quotes map with symbols loaded from the watchlist
+// file, without re-triggering a save.
+func (t *stockwatcher) seed(symbols []string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	for _, symbol := range symbols {
+		if _, ok := t.quotes[symbol]; !ok {
+			t.quotes[symbol] = &Quote{Symbol: symbol}
+		}
+	}
 }
 
-// Resource contains the actual JSON obj with the symbol data
-type Resource struct {
-	Classname string `json:"classname"`
-	Fields    Fields `json:"fields"`
+// add takes the given symbol, populates a key in the quotes map and
+// persists the updated watchlist.
+func (t *stockwatcher) add(symbol string) {
+	t.m.Lock()
+	_, exists := t.quotes[symbol]
+	if !exists {
+		t.quotes[symbol] = &Quote{Symbol: symbol}
+	}
+	t.m.Unlock()
+	if !exists {
+		t.save()
+		t.notifyChange()
+	}
 }
 
-// Fields holds all of the retrieved data from the API call
-type Fields struct {
-	Name    string `json:"name"`    // name of company
-	Price   string `json:"price"`   // current price
-	Symbol  string `json:"symbol"`  // stock symbol
-	TS      string `json:"ts"`      //
-	Type    string `json:"type"`    // type of stock (equity, etc...)
-	UTCTime string `json:"utctime"` // time in UTC
-	Volume  string `json:"volume"`  // shares traded
+// remove drops the given symbol from the quotes map and persists the
+// updated watchlist.
+func (t *stockwatcher) remove(symbol string) {
+	t.m.Lock()
+	_, exists := t.quotes[symbol]
+	delete(t.quotes, symbol)
+	delete(t.prevPrice, symbol)
+	t.m.Unlock()
+	if exists {
+		t.save()
+		t.notifyChange()
+	}
 }
 
-// stockwatcher holds the relevant data for the running instance
-type stockwatcher struct {
-	quotes   map[string]map[string]float64
-	interval time.Duration
-	m        *sync.Mutex
+// notifyChange tells a StreamingProvider-backed watcher's subscription
+// to refresh after the watchlist changes. It's a no-op for polled
+// providers, which just pick up the new symbol on the next tick.
+func (t *stockwatcher) notifyChange() {
+	if t.onChange != nil {
+		t.onChange()
+	}
 }
 
-// NewStockWatcher returns a new instance of stockwatcher with the given parameters
-func NewStockWatcher(i time.Duration) *stockwatcher {
-	return &stockwatcher{
-		quotes:   make(map[string]map[string]float64),
-		interval: i,
-		m:        &sync.Mutex{},
+// symbols returns the currently watched symbols.
+func (t *stockwatcher) symbols() []string {
+	t.m.Lock()
+	defer t.m.Unlock()
+	symbols := make([]string, 0, len(t.quotes))
+	for k := range t.quotes {
+		symbols = append(symbols, k)
 	}
+	return symbols
 }
 
-// add takes the given symbol and populates a key in the quotes map
-func (t *stockwatcher) add(symbol string) {
+// save persists the current watchlist and alerts to t.path.
+func (t *stockwatcher) save() {
 	t.m.Lock()
-	defer t.m.Unlock()
-	if _, ok := t.quotes[symbol]; !ok {
-		t.quotes[symbol] = map[string]float64{}
+	symbols := make([]string, 0, len(t.quotes))
+	for k := range t.quotes {
+		symbols = append(symbols, k)
+	}
+	alerts := append([]Alert(nil), t.alerts...)
+	t.m.Unlock()
+	sort.Strings(symbols)
+
+	if err := saveWatchlist(t.path, symbols, alerts); err != nil {
+		log.Println("unable to save watchlist:", err)
 	}
 }
 
-// updateStock populates stockwatcher struct with stock prices
-func (t *stockwatcher) updateStock(symbol string, price float64) {
+// updateStock records the latest quote for a symbol, remembering the
+// price it's replacing so formatData can still show direction of travel,
+// then evaluates any alerts registered against that symbol. A quote with
+// no price (e.g. from a provider that failed to fetch this symbol) is
+// stored so the UI keeps showing the symbol, but it's excluded from
+// prevPrice, tick history, and alert evaluation so it can't masquerade
+// as a real reading.
+// Quotes for symbols no longer on the watchlist are dropped: a streaming
+// provider subscribed to a wildcard topic (e.g. the mqtt provider's
+// default stock/response/#) may keep pushing quotes for a symbol after
+// the user has removed it with `-SYM`.
+func (t *stockwatcher) updateStock(q Quote) {
 	t.m.Lock()
 	defer t.m.Unlock()
-	t.quotes[symbol] = map[string]float64{
-		"previous": t.quotes[symbol]["current"],
-		"current":  price,
+	cur, ok := t.quotes[q.Symbol]
+	if !ok {
+		return
 	}
-}
+	if cur.Price != 0 {
+		t.prevPrice[q.Symbol] = cur.Price
+	}
+	t.quotes[q.Symbol] = &q
 
-// query will retrieve data for a given symbol
-func query(symbol string) (*Stock, error) {
-	data := &Stock{}
-	client := http.Client{
-		Timeout: TIMEOUT,
+	if q.Price == 0 {
+		return
 	}
 
-	resp, err := client.Get(fmt.Sprintf(URL, symbol))
-	if err != nil {
-		return nil, errors.New("unable to retrive symbol data")
+	if t.history != nil {
+		if err := t.history.record(q.Symbol, time.Now(), q.Price); err != nil {
+			log.Println("unable to record tick:", err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		log.Fatalln(err)
+	for i := range t.alerts {
+		a := &t.alerts[i]
+		if a.Symbol != q.Symbol {
+			continue
+		}
+		switch {
+		case a.crossed(q.Price) && !a.Fired:
+			a.Fired = true
+			t.flashed[q.Symbol] = true
+			fire(a, q.Price, t.onAlert)
+		case !a.crossed(q.Price):
+			a.Fired = false // condition released, allow it to fire again on the next crossing
+		}
 	}
-	return data, nil
 }
 
-// convertPrice converts the given string to a float64 value
-func convertPrice(p string) float64 {
-	price, err := strconv.ParseFloat(p, 64)
-	if err != nil {
-		log.Fatalln(err)
-		os.Exit(1)
-	}
-	return price
+// seedAlerts populates the alerts slice from persisted state, without
+// re-triggering a save.
+func (t *stockwatcher) seedAlerts(alerts []Alert) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.alerts = append(t.alerts, alerts...)
 }
 
-// runner goes through and gets the data for each symbol
-func (t *stockwatcher) runner() {
-	var wg sync.WaitGroup
-	for k, _ := range t.quotes {
-		wg.Add(1)
-		go func(k string) {
-			defer wg.Done()
-			stock, err := query(k)
-			if err != nil { // if we can't get a response from the API, put 0.00's in and keep going
-				t.updateStock(k, 0.00)
-				return
-			}
-			t.updateStock(stock.List.Resources[0].Resource.Fields.Symbol,
-				convertPrice(re.FindString(stock.List.Resources[0].Resource.Fields.Price)),
-			)
-		}(k)
+// addAlert registers a new price alert and persists it alongside the
+// watchlist.
+func (t *stockwatcher) addAlert(a Alert) {
+	t.m.Lock()
+	t.alerts = append(t.alerts, a)
+	t.m.Unlock()
+	t.save()
+}
+
+// runner fetches the latest data for every watched symbol through the
+// configured QuoteProvider. It's a no-op when the watcher is backed by
+// a StreamingProvider instead.
+func (t *stockwatcher) runner(ctx context.Context) {
+	if t.provider == nil {
+		return
+	}
+
+	quotes, err := t.provider.Fetch(ctx, t.symbols())
+	if err != nil { // if we can't reach the provider, leave the last known values in place
+		return
 	}
-	wg.Wait()
+	for _, q := range quotes {
+		t.updateStock(q)
+	}
+}
+
+// rowPrefixFormat mirrors the column segments the render loop prints
+// before the sparkline (SYM, LAST, CHANGE, CHG%, OPEN, LOW, HIGH, 52WL,
+// 52WH, VOLUME) - keep it in sync with the printSeg calls in the row
+// loop below. It exists so the sparkline's on-screen column width can
+// be computed from it instead of a hand-counted literal.
+const rowPrefixFormat = "%-6s %10s %10s %7s %s %10s %10s %10s %10s %10s %12s  "
+
+// rowPrefixWidth returns the number of columns rowPrefixFormat occupies,
+// used to fit the sparkline column to the terminal instead of letting it
+// run off the right edge. The %s placeholder (the arrow) has no width
+// specifier of its own, so it's measured with a single-rune value like
+// the real UP/DOWN/"-" arrows the row loop prints there.
+func rowPrefixWidth() int {
+	return runewidth.StringWidth(fmt.Sprintf(rowPrefixFormat, "", "", "", "", "-", "", "", "", "", "", ""))
 }
 
 // formatData formats the given data for printing
@@ -179,74 +259,163 @@ func (t *stockwatcher) formatData() {
 	}
 	sort.Strings(keys) // alphabetize keys
 
+	sparkWidth := t.sparkWidth
+	if sparkWidth <= 0 {
+		sparkWidth = 20
+	}
+	if w, _ := termbox.Size(); w-1-rowPrefixWidth() < sparkWidth {
+		sparkWidth = w - 1 - rowPrefixWidth()
+	}
+	if sparkWidth < 0 {
+		sparkWidth = 0
+	}
+	header := fmt.Sprintf("%-6s %10s %10s %8s %10s %10s %10s %10s %10s %12s  %s",
+		"SYM", "LAST", "CHANGE", "CHG%", "OPEN", "LOW", "HIGH", "52WL", "52WH", "VOLUME", "HISTORY")
+	printSeg(1, 0, header, termbox.ColorWhite|termbox.AttrBold, termbox.ColorDefault)
+
 	pos := 1
 	for _, k := range keys {
-		// print format for first run or if not change detected from previous run
-		if t.quotes[k]["previous"] == 0.00 || t.quotes[k]["previous"] == t.quotes[k]["current"] {
-			printTb(1,
-				pos,
-				fmt.Sprintf("%-6s %-7v %11s %-4s\n", k, t.quotes[k]["current"], "%", "-"),
-				termbox.ColorWhite, termbox.ColorDefault,
-			)
-			pos++
-			// print format in green if current price being is greater than previous price
-		} else if t.quotes[k]["current"] > t.quotes[k]["previous"] {
-			printTb(1,
-				pos,
-				fmt.Sprintf("%-6s %-7v +%-.6f %% %-4s\n", k, t.quotes[k]["current"], t.quotes[k]["current"]/t.quotes[k]["previous"], UP),
-				termbox.ColorGreen,
-				termbox.ColorDefault,
-			)
-			pos++
-			// print format in red if current price being is lesser than previous price
-		} else {
-			printTb(1,
-				pos,
-				fmt.Sprintf("%-6s %-7v -%-.6f %% %-4s\n", k, t.quotes[k]["current"], t.quotes[k]["current"]/t.quotes[k]["previous"], DOWN),
-				termbox.ColorRed,
-				termbox.ColorDefault,
-			)
-			pos++
+		q := t.quotes[k]
+		previous := t.prevPrice[k]
+
+		// a symbol that just crossed an alert threshold flashes for one
+		// render pass so it's easy to spot before it scrolls by.
+		bg := termbox.ColorDefault
+		if t.flashed[k] {
+			bg = termbox.ColorYellow
+			delete(t.flashed, k)
+		}
+
+		changeColor := termbox.ColorWhite
+		arrow := "-"
+		switch {
+		case q.Price > previous && previous != 0.00:
+			changeColor, arrow = termbox.ColorGreen, UP
+		case q.Price < previous && previous != 0.00:
+			changeColor, arrow = termbox.ColorRed, DOWN
 		}
+
+		x := 1
+		x = printSeg(x, pos, fmt.Sprintf("%-6s ", k), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.Price)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.Change)), changeColor, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%7s %s ", fmtNum(q.ChangePercent), arrow), changeColor, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.Open)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.DayLow)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.DayHigh)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.FiftyTwoWkLow)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%10s ", fmtNum(q.FiftyTwoWkHigh)), termbox.ColorWhite, bg)
+		x = printSeg(x, pos, fmt.Sprintf("%12s  ", fmtVol(q.Volume)), termbox.ColorWhite, bg)
+		_ = printSeg(x, pos, t.sparkline(k, sparkWidth), termbox.ColorCyan, bg)
+		pos++
+	}
+}
+
+// sparkline returns the rendered intraday sparkline for symbol, or a
+// blank column when no tick history has been recorded for it.
+func (t *stockwatcher) sparkline(symbol string, width int) string {
+	if t.history == nil {
+		return strings.Repeat(" ", width)
 	}
+	samples, err := t.history.recent(symbol, width)
+	if err != nil {
+		return strings.Repeat(" ", width)
+	}
+	return sparkline(samples, width)
 }
 
-// printTb prints the given data out to the screen
-func printTb(x, y int, msg string, fg, bg termbox.Attribute) {
+// fmtNum renders a float for display, falling back to "-" when the
+// provider didn't populate the field.
+func fmtNum(v float64) string {
+	if v == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// fmtVol renders a volume for display, falling back to "-" when the
+// provider didn't populate the field.
+func fmtVol(v uint64) string {
+	if v == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// printSeg prints msg starting at (x, y) and returns the cursor position
+// immediately after it, so callers can colorize adjoining segments of
+// the same row independently.
+func printSeg(x, y int, msg string, fg, bg termbox.Attribute) int {
 	for _, c := range []rune(msg) {
 		termbox.SetCell(x, y, c, fg, bg)
 		x += runewidth.RuneWidth(c)
 	}
-	termbox.Flush()
+	return x
 }
 
 func main() {
 	flag.Parse()
 
-	// make sure we got what was expected from the CLI
-	if flag.NFlag() != 2 || *symbolFlag == "" {
-		flag.Usage()
-		os.Exit(1)
+	dbPath := *dbFlag
+	if dbPath == "" {
+		var err error
+		dbPath, err = defaultDBPath()
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	t := NewStockWatcher(time.Duration(*intervalFlag) * time.Second)
+	if *exportFlag != "" {
+		if *exportFlag != "csv" {
+			log.Fatalf("unsupported -export format %q", *exportFlag)
+		}
+		history, err := openTickStore(dbPath, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer history.Close()
+		if err := history.exportCSV(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	// check if more than one symbol has been given
-	switch {
-	case strings.Contains(*symbolFlag, ","):
-		for _, a := range strings.Split(*symbolFlag, ",") {
-			t.add(a)
+	path := *watchlistFlag
+	if path == "" {
+		var err error
+		path, err = defaultWatchlistPath()
+		if err != nil {
+			log.Fatal(err)
 		}
-	default:
-		t.add(*symbolFlag)
 	}
 
-	// initialize termbox
-	err := termbox.Init()
+	symbols, alerts, err := loadWatchlist(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	history, err := openTickStore(dbPath, *historyFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
-	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+
+	rawProvider, err := newProvider(*providerFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	qp, _ := rawProvider.(QuoteProvider) // nil when the provider only streams
+
+	t := NewStockWatcher(time.Duration(*intervalFlag)*time.Second, qp, path, *onAlertFlag)
+	t.history = history
+	t.sparkWidth = *sparkWidthFlag
+	t.seed(symbols)
+	t.seedAlerts(alerts)
+
+	// initialize termbox
+	if err := termbox.Init(); err != nil {
+		log.Fatal(err)
+	}
 
 	event := make(chan termbox.Event)
 	go func() {
@@ -256,21 +425,68 @@ func main() {
 		}
 	}()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var streamCh <-chan Quote
+	if sp, ok := rawProvider.(StreamingProvider); ok {
+		t.streaming = true
+
+		var streamCancel context.CancelFunc
+		subscribe := func() {
+			if streamCancel != nil {
+				streamCancel()
+			}
+			subCtx, c := context.WithCancel(ctx)
+			streamCancel = c
+			ch, err := sp.Stream(subCtx, t.symbols())
+			if err != nil {
+				log.Println("unable to subscribe:", err)
+				streamCh = nil
+				return
+			}
+			streamCh = ch
+		}
+		t.onChange = subscribe
+		subscribe()
+	}
+
+	cl := &commandLine{}
+
 loop:
 	for {
-		t.runner()
-		t.formatData()
+		render(t, cl)
 
-		// Poll key event or timeout (maybe)
 		select {
-		case <-event:
-			break loop
+		case ev := <-event:
+			cmd, quit := cl.handleEvent(ev)
+			if quit {
+				break loop
+			}
+			if cmd != "" {
+				execute(t, ctx, cmd)
+			}
+		case q, ok := <-streamCh:
+			if !ok {
+				streamCh = nil
+				continue loop
+			}
+			t.updateStock(q)
 		case <-time.After(t.interval):
-			continue loop
+			t.runner(ctx)
 		}
 	}
 	close(event)
-	time.Sleep(1 * time.Second)
 	termbox.Close()
+	history.Close()
 	os.Exit(0) // close out on a good note
 }
+
+// render draws the watchlist and command line and flushes the screen.
+func render(t *stockwatcher, cl *commandLine) {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	t.formatData()
+	_, h := termbox.Size()
+	cl.render(h - 1)
+	termbox.Flush()
+}
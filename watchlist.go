@@ -0,0 +1,63 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// watchlistFile is the on-disk JSON representation of a persisted
+// watchlist.
+type watchlistFile struct {
+	Symbols []string `json:"symbols"`
+	Alerts  []Alert  `json:"alerts,omitempty"`
+}
+
+// defaultWatchlistPath returns the default location of the persisted
+// watchlist, ~/.stockticker.
+func defaultWatchlistPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".stockticker"), nil
+}
+
+// loadWatchlist reads the persisted symbols and alerts from path. A
+// missing file isn't an error, it just means nothing has been saved yet.
+func loadWatchlist(path string) ([]string, []Alert, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var wl watchlistFile
+	if err := json.Unmarshal(data, &wl); err != nil {
+		return nil, nil, err
+	}
+	return wl.Symbols, wl.Alerts, nil
+}
+
+// saveWatchlist persists the given symbols and alerts to path as JSON.
+func saveWatchlist(path string, symbols []string, alerts []Alert) error {
+	data, err := json.MarshalIndent(watchlistFile{Symbols: symbols, Alerts: alerts}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}